@@ -1,14 +1,23 @@
 package rest
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/evergreen-ci/cedar/rest/data"
 	"github.com/evergreen-ci/cedar/util"
 	"github.com/evergreen-ci/gimlet"
+	"github.com/klauspost/compress/zstd"
 	"github.com/mongodb/grip"
 	"github.com/pkg/errors"
 )
@@ -26,20 +35,184 @@ const (
 	trueString    = "true"
 	softSizeLimit = 10 * 1024 * 1024
 	baseURL       = "https://cedar.mongodb.com"
+
+	deadlineParam            = "deadline"
+	idleTimeoutParam         = "idle_timeout"
+	defaultStreamIdleTimeout = 10 * time.Minute
+	streamPollInterval       = time.Second
+
+	acceptEncodingHeader  = "Accept-Encoding"
+	contentEncodingHeader = "Content-Encoding"
+	varyHeader            = "Vary"
+	encodingGzip          = "gzip"
+	encodingZstd          = "zstd"
+
+	matchParam       = "match"
+	excludeParam     = "exclude"
+	minPriorityParam = "min_priority"
+	fieldsParam      = "fields"
+	countOnlyParam   = "count_only"
 )
 
+// compressedBytesServed counts the compressed bytes written back to
+// clients. It is an in-process counter only; it is not registered with or
+// exported to any metrics system.
+var compressedBytesServed int64
+
+// negotiateContentEncoding picks a response content encoding from the
+// request's Accept-Encoding header, preferring zstd over gzip when both are
+// accepted with the same weight. It respects q-values, including "q=0" as an
+// explicit refusal of an encoding, per RFC 7231 section 5.3.4.
+func negotiateContentEncoding(r *http.Request) string {
+	accept := r.Header.Get(acceptEncodingHeader)
+
+	var bestEncoding string
+	var bestQ float64
+	for _, part := range strings.Split(accept, ",") {
+		encoding, q := parseQValue(part)
+		if encoding != encodingGzip && encoding != encodingZstd {
+			continue
+		}
+		if q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && encoding == encodingZstd) {
+			bestEncoding = encoding
+			bestQ = q
+		}
+	}
+
+	return bestEncoding
+}
+
+// parseQValue splits a single Accept-Encoding entry, such as "gzip;q=0.5",
+// into its encoding name and weight. It defaults to a weight of 1 when no
+// q-value is present.
+func parseQValue(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	encoding := strings.ToLower(strings.TrimSpace(fields[0]))
+	if encoding == "" {
+		return "", 0
+	}
+
+	q := 1.0
+	for _, param := range fields[1:] {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(name)) != "q" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		q = parsed
+	}
+
+	return encoding, q
+}
+
+// compressBuildloggerData compresses data using the given encoding, one of
+// encodingGzip or encodingZstd, and records the number of bytes served.
+func compressBuildloggerData(data []byte, encoding string) ([]byte, error) {
+	var compressed []byte
+
+	switch encoding {
+	case encodingGzip:
+		buf := &bytes.Buffer{}
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, errors.Wrap(err, "problem gzip compressing response")
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Wrap(err, "problem closing gzip writer")
+		}
+		compressed = buf.Bytes()
+	case encodingZstd:
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "problem creating zstd writer")
+		}
+		defer w.Close()
+		compressed = w.EncodeAll(data, nil)
+	default:
+		return data, nil
+	}
+
+	atomic.AddInt64(&compressedBytesServed, int64(len(compressed)))
+	return compressed, nil
+}
+
+// logFilterParams holds the match/exclude/min_priority/fields/count_only
+// query params shared by the buildlogger GET handlers, letting callers push
+// filtering down to the connector instead of piping the response through
+// `grep` client-side.
+type logFilterParams struct {
+	match       string
+	exclude     string
+	minPriority int
+	fields      []string
+	countOnly   bool
+}
+
+// parseLogFilterParams parses and validates the match, exclude,
+// min_priority, fields, and count_only query params.
+func parseLogFilterParams(vals url.Values) (logFilterParams, error) {
+	var params logFilterParams
+	catcher := grip.NewBasicCatcher()
+
+	params.match = vals.Get(matchParam)
+	if params.match != "" {
+		_, err := regexp.Compile(params.match)
+		catcher.Add(errors.Wrapf(err, "problem compiling match regexp '%s'", params.match))
+	}
+	params.exclude = vals.Get(excludeParam)
+	if params.exclude != "" {
+		_, err := regexp.Compile(params.exclude)
+		catcher.Add(errors.Wrapf(err, "problem compiling exclude regexp '%s'", params.exclude))
+	}
+	if v := vals.Get(minPriorityParam); v != "" {
+		n, err := strconv.Atoi(v)
+		catcher.Add(err)
+		params.minPriority = n
+	}
+	if v := vals.Get(fieldsParam); v != "" {
+		params.fields = strings.Split(v, ",")
+	}
+	params.countOnly = vals.Get(countOnlyParam) == trueString
+
+	return params, catcher.Resolve()
+}
+
+// newBuildloggerCountResponder returns the number of matching lines in data
+// with no body, for requests made with count_only=true.
+func newBuildloggerCountResponder(data []byte) gimlet.Responder {
+	var count int
+	if len(data) > 0 {
+		count = bytes.Count(data, []byte("\n"))
+		if data[len(data)-1] != '\n' {
+			count++
+		}
+	}
+
+	return gimlet.NewJSONResponse(struct {
+		Count int `json:"count"`
+	}{Count: count})
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 //
 // GET /buildlogger/{id}
 
 type logGetByIDHandler struct {
-	id            string
-	tr            util.TimeRange
-	printTime     bool
-	printPriority bool
-	paginate      bool
-	limit         int
-	sc            data.Connector
+	id             string
+	tr             util.TimeRange
+	printTime      bool
+	printPriority  bool
+	paginate       bool
+	limit          int
+	acceptEncoding string
+	filter         logFilterParams
+	sc             data.Connector
 }
 
 func makeGetLogByID(sc data.Connector) gimlet.RouteHandler {
@@ -65,12 +238,15 @@ func (h *logGetByIDHandler) Parse(_ context.Context, r *http.Request) error {
 	h.printTime = vals.Get(printTime) == trueString
 	h.printPriority = vals.Get(printPriority) == trueString
 	h.paginate = vals.Get(paginate) == trueString
+	h.acceptEncoding = negotiateContentEncoding(r)
 	h.tr, err = parseTimeRange(vals, logStartAt, logEndAt)
 	catcher.Add(err)
 	if len(vals[limit]) > 0 {
 		h.limit, err = strconv.Atoi(vals[limit][0])
 		catcher.Add(err)
 	}
+	h.filter, err = parseLogFilterParams(vals)
+	catcher.Add(err)
 
 	return catcher.Resolve()
 }
@@ -78,11 +254,16 @@ func (h *logGetByIDHandler) Parse(_ context.Context, r *http.Request) error {
 // Run calls FindLogByID and returns the log.
 func (h *logGetByIDHandler) Run(ctx context.Context) gimlet.Responder {
 	opts := data.BuildloggerOptions{
-		ID:            h.id,
-		TimeRange:     h.tr,
-		PrintTime:     h.printTime,
-		PrintPriority: h.printPriority,
-		Limit:         h.limit,
+		ID:             h.id,
+		TimeRange:      h.tr,
+		PrintTime:      h.printTime,
+		PrintPriority:  h.printPriority,
+		Limit:          h.limit,
+		AcceptEncoding: h.acceptEncoding,
+		Match:          h.filter.match,
+		Exclude:        h.filter.exclude,
+		MinPriority:    h.filter.minPriority,
+		Fields:         h.filter.fields,
 	}
 	if h.paginate && opts.Limit <= 0 {
 		opts.SoftSizeLimit = softSizeLimit
@@ -91,8 +272,11 @@ func (h *logGetByIDHandler) Run(ctx context.Context) gimlet.Responder {
 	if err != nil {
 		return gimlet.MakeJSONErrorResponder(errors.Wrapf(err, "Error getting log by id '%s'", h.id))
 	}
+	if h.filter.countOnly {
+		return newBuildloggerCountResponder(data)
+	}
 
-	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated)
+	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated, h.acceptEncoding)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -138,17 +322,19 @@ func (h *logMetaGetByIDHandler) Run(ctx context.Context) gimlet.Responder {
 // GET /buildlogger/task_id/{task_id}
 
 type logGetByTaskIDHandler struct {
-	id            string
-	procName      string
-	execution     int
-	tags          []string
-	tr            util.TimeRange
-	printTime     bool
-	printPriority bool
-	paginate      bool
-	n             int
-	limit         int
-	sc            data.Connector
+	id             string
+	procName       string
+	execution      int
+	tags           []string
+	tr             util.TimeRange
+	printTime      bool
+	printPriority  bool
+	paginate       bool
+	n              int
+	limit          int
+	acceptEncoding string
+	filter         logFilterParams
+	sc             data.Connector
 }
 
 func makeGetLogByTaskID(sc data.Connector) gimlet.RouteHandler {
@@ -176,6 +362,7 @@ func (h *logGetByTaskIDHandler) Parse(_ context.Context, r *http.Request) error
 	h.printTime = vals.Get(printTime) == trueString
 	h.printPriority = vals.Get(printPriority) == trueString
 	h.paginate = vals.Get(paginate) == trueString
+	h.acceptEncoding = negotiateContentEncoding(r)
 	h.tr, err = parseTimeRange(vals, logStartAt, logEndAt)
 	catcher.Add(err)
 	if len(vals[execution]) > 0 {
@@ -190,6 +377,8 @@ func (h *logGetByTaskIDHandler) Parse(_ context.Context, r *http.Request) error
 		h.n, err = strconv.Atoi(vals["n"][0])
 		catcher.Add(err)
 	}
+	h.filter, err = parseLogFilterParams(vals)
+	catcher.Add(err)
 
 	return catcher.Resolve()
 }
@@ -197,15 +386,20 @@ func (h *logGetByTaskIDHandler) Parse(_ context.Context, r *http.Request) error
 // Run calls FindLogsByTaskID and returns the merged logs.
 func (h *logGetByTaskIDHandler) Run(ctx context.Context) gimlet.Responder {
 	opts := data.BuildloggerOptions{
-		TaskID:        h.id,
-		Execution:     h.execution,
-		ProcessName:   h.procName,
-		Tags:          h.tags,
-		TimeRange:     h.tr,
-		PrintTime:     h.printTime,
-		PrintPriority: h.printPriority,
-		Limit:         h.limit,
-		Tail:          h.n,
+		TaskID:         h.id,
+		Execution:      h.execution,
+		ProcessName:    h.procName,
+		Tags:           h.tags,
+		TimeRange:      h.tr,
+		PrintTime:      h.printTime,
+		PrintPriority:  h.printPriority,
+		Limit:          h.limit,
+		Tail:           h.n,
+		AcceptEncoding: h.acceptEncoding,
+		Match:          h.filter.match,
+		Exclude:        h.filter.exclude,
+		MinPriority:    h.filter.minPriority,
+		Fields:         h.filter.fields,
 	}
 	if h.paginate && opts.Limit <= 0 && opts.Tail <= 0 {
 		opts.SoftSizeLimit = softSizeLimit
@@ -214,8 +408,11 @@ func (h *logGetByTaskIDHandler) Run(ctx context.Context) gimlet.Responder {
 	if err != nil {
 		return gimlet.MakeJSONErrorResponder(errors.Wrapf(err, "Error getting logs by task id '%s'", h.id))
 	}
+	if h.filter.countOnly {
+		return newBuildloggerCountResponder(data)
+	}
 
-	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated)
+	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated, h.acceptEncoding)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -269,15 +466,17 @@ func (h *logMetaGetByTaskIDHandler) Run(ctx context.Context) gimlet.Responder {
 // GET /buildlogger/test_name/{task_id}/{test_name}
 
 type logGetByTestNameHandler struct {
-	id            string
-	name          string
-	tags          []string
-	tr            util.TimeRange
-	printTime     bool
-	printPriority bool
-	paginate      bool
-	limit         int
-	sc            data.Connector
+	id             string
+	name           string
+	tags           []string
+	tr             util.TimeRange
+	printTime      bool
+	printPriority  bool
+	paginate       bool
+	limit          int
+	acceptEncoding string
+	filter         logFilterParams
+	sc             data.Connector
 }
 
 func makeGetLogByTestName(sc data.Connector) gimlet.RouteHandler {
@@ -305,12 +504,15 @@ func (h *logGetByTestNameHandler) Parse(_ context.Context, r *http.Request) erro
 	h.printTime = vals.Get(printTime) == trueString
 	h.printPriority = vals.Get(printPriority) == trueString
 	h.paginate = vals.Get(paginate) == trueString
+	h.acceptEncoding = negotiateContentEncoding(r)
 	h.tr, err = parseTimeRange(vals, logStartAt, logEndAt)
 	catcher.Add(err)
 	if len(vals[limit]) > 0 {
 		h.limit, err = strconv.Atoi(vals[limit][0])
 		catcher.Add(err)
 	}
+	h.filter, err = parseLogFilterParams(vals)
+	catcher.Add(err)
 
 	return catcher.Resolve()
 }
@@ -318,13 +520,18 @@ func (h *logGetByTestNameHandler) Parse(_ context.Context, r *http.Request) erro
 // Run calls FindLogsByTestName and returns the merged logs.
 func (h *logGetByTestNameHandler) Run(ctx context.Context) gimlet.Responder {
 	opts := data.BuildloggerOptions{
-		TaskID:        h.id,
-		TestName:      h.name,
-		Tags:          h.tags,
-		TimeRange:     h.tr,
-		PrintTime:     h.printTime,
-		PrintPriority: h.printPriority,
-		Limit:         h.limit,
+		TaskID:         h.id,
+		TestName:       h.name,
+		Tags:           h.tags,
+		TimeRange:      h.tr,
+		PrintTime:      h.printTime,
+		PrintPriority:  h.printPriority,
+		Limit:          h.limit,
+		AcceptEncoding: h.acceptEncoding,
+		Match:          h.filter.match,
+		Exclude:        h.filter.exclude,
+		MinPriority:    h.filter.minPriority,
+		Fields:         h.filter.fields,
 	}
 	if h.paginate && opts.Limit <= 0 {
 		opts.SoftSizeLimit = softSizeLimit
@@ -333,8 +540,11 @@ func (h *logGetByTestNameHandler) Run(ctx context.Context) gimlet.Responder {
 	if err != nil {
 		return gimlet.MakeJSONErrorResponder(errors.Wrapf(err, "Error getting logs by test name '%s'", h.name))
 	}
+	if h.filter.countOnly {
+		return newBuildloggerCountResponder(data)
+	}
 
-	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated)
+	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated, h.acceptEncoding)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -397,16 +607,18 @@ func (h *logMetaGetByTestNameHandler) Run(ctx context.Context) gimlet.Responder
 // GET /buildlogger/test_name/{task_id}/{test_name}/group/{group_id}
 
 type logGroupHandler struct {
-	id            string
-	name          string
-	groupID       string
-	tags          []string
-	tr            util.TimeRange
-	printTime     bool
-	printPriority bool
-	paginate      bool
-	limit         int
-	sc            data.Connector
+	id             string
+	name           string
+	groupID        string
+	tags           []string
+	tr             util.TimeRange
+	printTime      bool
+	printPriority  bool
+	paginate       bool
+	limit          int
+	acceptEncoding string
+	filter         logFilterParams
+	sc             data.Connector
 }
 
 func makeGetLogGroup(sc data.Connector) gimlet.RouteHandler {
@@ -435,6 +647,7 @@ func (h *logGroupHandler) Parse(_ context.Context, r *http.Request) error {
 	h.printTime = vals.Get(printTime) == trueString
 	h.printPriority = vals.Get(printPriority) == trueString
 	h.paginate = vals.Get(paginate) == trueString
+	h.acceptEncoding = negotiateContentEncoding(r)
 	if vals.Get(logStartAt) != "" || vals.Get(logEndAt) != "" {
 		h.tr, err = parseTimeRange(vals, logStartAt, logEndAt)
 		catcher.Add(err)
@@ -443,6 +656,8 @@ func (h *logGroupHandler) Parse(_ context.Context, r *http.Request) error {
 		h.limit, err = strconv.Atoi(vals[limit][0])
 		catcher.Add(err)
 	}
+	h.filter, err = parseLogFilterParams(vals)
+	catcher.Add(err)
 
 	return catcher.Resolve()
 }
@@ -450,13 +665,18 @@ func (h *logGroupHandler) Parse(_ context.Context, r *http.Request) error {
 // Run calls FindGroupedLogs and returns the merged logs.
 func (h *logGroupHandler) Run(ctx context.Context) gimlet.Responder {
 	opts := data.BuildloggerOptions{
-		TaskID:        h.id,
-		TestName:      h.name,
-		Tags:          append(h.tags, h.groupID),
-		TimeRange:     h.tr,
-		PrintTime:     h.printTime,
-		PrintPriority: h.printPriority,
-		Limit:         h.limit,
+		TaskID:         h.id,
+		TestName:       h.name,
+		Tags:           append(h.tags, h.groupID),
+		TimeRange:      h.tr,
+		PrintTime:      h.printTime,
+		PrintPriority:  h.printPriority,
+		Limit:          h.limit,
+		AcceptEncoding: h.acceptEncoding,
+		Match:          h.filter.match,
+		Exclude:        h.filter.exclude,
+		MinPriority:    h.filter.minPriority,
+		Fields:         h.filter.fields,
 	}
 	if h.paginate && opts.Limit <= 0 {
 		opts.SoftSizeLimit = softSizeLimit
@@ -482,12 +702,307 @@ func (h *logGroupHandler) Run(ctx context.Context) gimlet.Responder {
 		return gimlet.MakeJSONErrorResponder(errors.Wrapf(err,
 			"Error getting grouped logs with task_id/test_name/group_id '%s/%s/%s'", h.id, h.name, h.groupID))
 	}
+	if h.filter.countOnly {
+		return newBuildloggerCountResponder(data)
+	}
 
-	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated)
+	return newBuildloggerResponder(data, h.tr.StartAt, next, paginated, h.acceptEncoding)
 }
 
-func newBuildloggerResponder(data []byte, last, next time.Time, paginated bool) gimlet.Responder {
+///////////////////////////////////////////////////////////////////////////////
+//
+// GET /buildlogger/{id}/stream
+// GET /buildlogger/task_id/{task_id}/stream
+// GET /buildlogger/test_name/{task_id}/{test_name}/stream
+
+// deadlineTimer closes its cancel channel once a deadline elapses, and lets
+// the deadline be pushed back (or brought forward) as new activity arrives.
+// Modeled on the deadlineTimer used by google/netstack's gonet adapter to
+// implement net.Conn-style deadlines over a non-blocking connection.
+type deadlineTimer struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{cancel: make(chan struct{})}
+}
+
+// setDeadline replaces the current deadline. If the previous timer had
+// already fired (and closed its cancel channel), a fresh channel is
+// installed so callers can keep selecting on done(). A deadline in the past
+// closes the channel immediately.
+func (dt *deadlineTimer) setDeadline(deadline time.Time) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	if dt.timer != nil && !dt.timer.Stop() {
+		dt.cancel = make(chan struct{})
+	}
+	// A previous deadline in the past closed the channel without a timer,
+	// so replace it here as well.
+	select {
+	case <-dt.cancel:
+		dt.cancel = make(chan struct{})
+	default:
+	}
+
+	until := time.Until(deadline)
+	if until <= 0 {
+		close(dt.cancel)
+		return
+	}
+
+	cancel := dt.cancel
+	dt.timer = time.AfterFunc(until, func() { close(cancel) })
+}
+
+func (dt *deadlineTimer) done() <-chan struct{} {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	return dt.cancel
+}
+
+// logStreamPollFunc fetches log lines appended after the given time, along
+// with the timestamp to resume from on the next poll.
+type logStreamPollFunc func(after time.Time) ([]byte, time.Time, error)
+
+// runBuildloggerStream writes newly available log lines to w as they are
+// polled, using flush-after-write so clients see a live tail -f-style feed
+// instead of a single buffered response. It returns once the hard deadline
+// elapses, the connection has been idle for longer than idleTimeout, or the
+// request context is done. The hard deadline and the idle timeout run on
+// independent timers: new activity resets the idle timer but never pushes
+// back the absolute deadline, so a caller asking for both gets a real upper
+// bound on how long the connection can stay open.
+func runBuildloggerStream(ctx context.Context, w http.ResponseWriter, idleTimeout time.Duration, deadline time.Time, poll logStreamPollFunc) {
+	var idleTimer *deadlineTimer
+	var idleDone <-chan struct{}
+	if idleTimeout > 0 {
+		idleTimer = newDeadlineTimer()
+		idleTimer.setDeadline(time.Now().Add(idleTimeout))
+		idleDone = idleTimer.done()
+	}
+
+	var hardDone <-chan struct{}
+	if !deadline.IsZero() {
+		hardTimer := newDeadlineTimer()
+		hardTimer.setDeadline(deadline)
+		hardDone = hardTimer.done()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Transfer-Encoding", "chunked")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+
+	var after time.Time
+	for {
+		select {
+		case <-idleDone:
+			return
+		case <-hardDone:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			chunk, next, err := poll(after)
+			if err != nil {
+				grip.Error(errors.Wrap(err, "problem polling for new buildlogger lines"))
+				return
+			}
+			if len(chunk) == 0 {
+				continue
+			}
+			after = next
+			if idleTimer != nil {
+				idleTimer.setDeadline(time.Now().Add(idleTimeout))
+			}
+			if _, err = w.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// parseStreamParams reads the deadline and idle_timeout query params shared
+// by all of the streaming handlers below.
+func parseStreamParams(vals url.Values) (idleTimeout time.Duration, deadline time.Time, err error) {
+	catcher := grip.NewBasicCatcher()
+
+	idleTimeout = defaultStreamIdleTimeout
+	if v := vals.Get(idleTimeoutParam); v != "" {
+		d, parseErr := time.ParseDuration(v)
+		catcher.Add(parseErr)
+		if parseErr == nil {
+			idleTimeout = d
+		}
+	}
+	if v := vals.Get(deadlineParam); v != "" {
+		d, parseErr := time.ParseDuration(v)
+		catcher.Add(parseErr)
+		if parseErr == nil {
+			deadline = time.Now().Add(d)
+		}
+	}
+
+	return idleTimeout, deadline, catcher.Resolve()
+}
+
+// writeStreamError writes a JSON error in the same shape as
+// gimlet.ErrorResponse, for the streaming handlers below that serve
+// http.Handler directly instead of going through gimlet.RouteHandler.
+func writeStreamError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	resp := gimlet.ErrorResponse{StatusCode: http.StatusBadRequest, Message: err.Error()}
+	grip.Error(errors.Wrap(json.NewEncoder(w).Encode(resp), "problem writing stream error response"))
+}
+
+type logStreamByIDHandler struct {
+	id          string
+	idleTimeout time.Duration
+	deadline    time.Time
+	sc          data.Connector
+}
+
+func makeStreamLogByID(sc data.Connector) http.Handler {
+	return &logStreamByIDHandler{sc: sc}
+}
+
+// ServeHTTP streams new log lines for the log with the given id as they are
+// written, using runBuildloggerStream.
+func (h *logStreamByIDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.id = gimlet.GetVars(r)["id"]
+	var err error
+	h.idleTimeout, h.deadline, err = parseStreamParams(r.URL.Query())
+	if err != nil {
+		writeStreamError(w, errors.Wrapf(err, "problem parsing stream params for log '%s'", h.id))
+		return
+	}
+
+	runBuildloggerStream(r.Context(), w, h.idleTimeout, h.deadline, func(after time.Time) ([]byte, time.Time, error) {
+		data, next, _, err := h.sc.FindLogByID(r.Context(), data.BuildloggerOptions{
+			ID:        h.id,
+			TimeRange: util.TimeRange{StartAt: after},
+			PrintTime: true,
+		})
+		return data, next, err
+	})
+}
+
+type logStreamByTaskIDHandler struct {
+	id          string
+	procName    string
+	execution   int
+	tags        []string
+	idleTimeout time.Duration
+	deadline    time.Time
+	sc          data.Connector
+}
+
+func makeStreamLogByTaskID(sc data.Connector) http.Handler {
+	return &logStreamByTaskIDHandler{sc: sc}
+}
+
+// ServeHTTP streams new log lines for the given task id as they are
+// written, using runBuildloggerStream.
+func (h *logStreamByTaskIDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.id = gimlet.GetVars(r)["task_id"]
+	vals := r.URL.Query()
+	h.procName = vals.Get(procName)
+	h.tags = vals[tags]
+	if len(vals[execution]) > 0 {
+		var err error
+		h.execution, err = strconv.Atoi(vals[execution][0])
+		if err != nil {
+			writeStreamError(w, errors.Wrapf(err, "problem parsing execution for task id '%s'", h.id))
+			return
+		}
+	}
+	var err error
+	h.idleTimeout, h.deadline, err = parseStreamParams(vals)
+	if err != nil {
+		writeStreamError(w, errors.Wrapf(err, "problem parsing stream params for task id '%s'", h.id))
+		return
+	}
+
+	runBuildloggerStream(r.Context(), w, h.idleTimeout, h.deadline, func(after time.Time) ([]byte, time.Time, error) {
+		data, next, _, err := h.sc.FindLogsByTaskID(r.Context(), data.BuildloggerOptions{
+			TaskID:      h.id,
+			Execution:   h.execution,
+			ProcessName: h.procName,
+			Tags:        h.tags,
+			TimeRange:   util.TimeRange{StartAt: after},
+			PrintTime:   true,
+		})
+		return data, next, err
+	})
+}
+
+type logStreamByTestNameHandler struct {
+	id          string
+	name        string
+	tags        []string
+	idleTimeout time.Duration
+	deadline    time.Time
+	sc          data.Connector
+}
+
+func makeStreamLogByTestName(sc data.Connector) http.Handler {
+	return &logStreamByTestNameHandler{sc: sc}
+}
+
+// ServeHTTP streams new log lines for the given task id and test name as
+// they are written, using runBuildloggerStream.
+func (h *logStreamByTestNameHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.id = gimlet.GetVars(r)["task_id"]
+	h.name = gimlet.GetVars(r)["test_name"]
+	vals := r.URL.Query()
+	h.tags = vals[tags]
+	var err error
+	h.idleTimeout, h.deadline, err = parseStreamParams(vals)
+	if err != nil {
+		writeStreamError(w, errors.Wrapf(err, "problem parsing stream params for test name '%s'", h.name))
+		return
+	}
+
+	runBuildloggerStream(r.Context(), w, h.idleTimeout, h.deadline, func(after time.Time) ([]byte, time.Time, error) {
+		data, next, _, err := h.sc.FindLogsByTestName(r.Context(), data.BuildloggerOptions{
+			TaskID:    h.id,
+			TestName:  h.name,
+			Tags:      h.tags,
+			TimeRange: util.TimeRange{StartAt: after},
+			PrintTime: true,
+		})
+		return data, next, err
+	})
+}
+
+// newBuildloggerResponder builds the text response for a buildlogger GET
+// handler, negotiating content encoding against acceptEncoding when the
+// caller sent a matching Accept-Encoding header.
+func newBuildloggerResponder(data []byte, last, next time.Time, paginated bool, acceptEncoding string) gimlet.Responder {
+	if acceptEncoding != "" {
+		compressed, err := compressBuildloggerData(data, acceptEncoding)
+		if err != nil {
+			return gimlet.MakeJSONErrorResponder(errors.Wrap(err, "problem compressing buildlogger response"))
+		}
+		data = compressed
+	}
+
 	resp := gimlet.NewTextResponse(data)
+	resp.AddHeader(varyHeader, acceptEncodingHeader)
+	if acceptEncoding != "" {
+		resp.AddHeader(contentEncodingHeader, acceptEncoding)
+	}
 
 	if paginated {
 		pages := &gimlet.ResponsePages{
@@ -0,0 +1,181 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadlineTimerClosesAfterDeadline(t *testing.T) {
+	dt := newDeadlineTimer()
+	dt.setDeadline(time.Now().Add(20 * time.Millisecond))
+
+	select {
+	case <-dt.done():
+		t.Fatal("deadline timer closed before its deadline")
+	default:
+	}
+
+	select {
+	case <-dt.done():
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("deadline timer never closed")
+	}
+}
+
+func TestDeadlineTimerPastDeadlineClosesImmediately(t *testing.T) {
+	dt := newDeadlineTimer()
+	dt.setDeadline(time.Now().Add(-time.Second))
+
+	select {
+	case <-dt.done():
+	default:
+		t.Fatal("deadline timer did not close for a deadline already in the past")
+	}
+}
+
+func TestDeadlineTimerResetExtendsDeadline(t *testing.T) {
+	dt := newDeadlineTimer()
+	dt.setDeadline(time.Now().Add(20 * time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	dt.setDeadline(time.Now().Add(100 * time.Millisecond))
+
+	select {
+	case <-dt.done():
+		t.Fatal("deadline timer closed before the reset deadline")
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+// TestRunBuildloggerStreamHardDeadlineIsNotExtendedByActivity guards against
+// the idle timeout reset clobbering the hard deadline: even though every
+// poll "succeeds" well inside the idle timeout, the hard deadline must still
+// cut the stream off. The first poll only runs after streamPollInterval, so
+// the hard deadline is set just past it to make sure activity happens first.
+func TestRunBuildloggerStreamHardDeadlineIsNotExtendedByActivity(t *testing.T) {
+	w := httptest.NewRecorder()
+	start := time.Now()
+
+	poll := func(after time.Time) ([]byte, time.Time, error) {
+		return []byte("line\n"), time.Now(), nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runBuildloggerStream(context.Background(), w, time.Hour, start.Add(streamPollInterval+200*time.Millisecond), poll)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(streamPollInterval + time.Second):
+		t.Fatalf("expected the hard deadline to bound the stream, still open after %s", time.Since(start))
+	}
+	if !strings.Contains(w.Body.String(), "line") {
+		t.Fatal("expected at least one poll to write a line before the hard deadline")
+	}
+}
+
+// TestRunBuildloggerStreamZeroIdleTimeoutNeverClosesOnItsOwn guards against
+// idle_timeout=0 being mistaken for "already elapsed": it should disable the
+// idle timeout rather than closing the stream immediately.
+func TestRunBuildloggerStreamZeroIdleTimeoutNeverClosesOnItsOwn(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	poll := func(after time.Time) ([]byte, time.Time, error) {
+		return nil, after, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runBuildloggerStream(ctx, w, 0, time.Time{}, poll)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("stream returned before the context was cancelled despite idleTimeout=0 and no deadline")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("stream did not stop after context cancellation")
+	}
+}
+
+func TestNegotiateContentEncodingPrefersZstdWhenTied(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(acceptEncodingHeader, "gzip, zstd")
+
+	if got := negotiateContentEncoding(r); got != encodingZstd {
+		t.Fatalf("got %q, want %q", got, encodingZstd)
+	}
+}
+
+func TestNegotiateContentEncodingHonorsQValues(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(acceptEncodingHeader, "zstd;q=0.2, gzip;q=0.8")
+
+	if got := negotiateContentEncoding(r); got != encodingGzip {
+		t.Fatalf("got %q, want %q", got, encodingGzip)
+	}
+}
+
+func TestNegotiateContentEncodingTreatsZeroQAsRefusal(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(acceptEncodingHeader, "zstd;q=0, gzip")
+
+	if got := negotiateContentEncoding(r); got != encodingGzip {
+		t.Fatalf("got %q, want %q", got, encodingGzip)
+	}
+}
+
+func TestNegotiateContentEncodingNoAcceptableEncoding(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(acceptEncodingHeader, "br")
+
+	if got := negotiateContentEncoding(r); got != "" {
+		t.Fatalf("got %q, want no encoding", got)
+	}
+}
+
+func TestParseLogFilterParamsRejectsInvalidMatchRegexp(t *testing.T) {
+	vals := url.Values{matchParam: {"("}}
+	if _, err := parseLogFilterParams(vals); err == nil {
+		t.Fatal("expected an error for an unparsable match regexp")
+	}
+}
+
+func TestParseLogFilterParamsRejectsInvalidExcludeRegexp(t *testing.T) {
+	vals := url.Values{excludeParam: {"("}}
+	if _, err := parseLogFilterParams(vals); err == nil {
+		t.Fatal("expected an error for an unparsable exclude regexp")
+	}
+}
+
+func TestParseLogFilterParamsRejectsNonIntegerMinPriority(t *testing.T) {
+	vals := url.Values{minPriorityParam: {"not-a-number"}}
+	if _, err := parseLogFilterParams(vals); err == nil {
+		t.Fatal("expected an error for a non-integer min_priority")
+	}
+}
+
+func TestParseLogFilterParamsSplitsFields(t *testing.T) {
+	vals := url.Values{fieldsParam: {"time,message"}}
+	params, err := parseLogFilterParams(vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params.fields) != 2 || params.fields[0] != "time" || params.fields[1] != "message" {
+		t.Fatalf("got %v, want [time message]", params.fields)
+	}
+}
@@ -0,0 +1,291 @@
+// Package data implements the cedar connector that mediates between the
+// REST layer and the underlying buildlogger storage: log metadata in
+// MongoDB and log chunks in a pail-backed bucket.
+package data
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"time"
+
+	"github.com/evergreen-ci/cedar/util"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// codec names used to tag how a chunk's bytes are stored.
+const (
+	codecNone = ""
+	codecGzip = "gzip"
+	codecZstd = "zstd"
+)
+
+// BuildloggerOptions describes a buildlogger log lookup: the time range,
+// pagination, the client's negotiated response encoding, and the
+// server-side filtering to apply before the log ever reaches the REST
+// layer.
+type BuildloggerOptions struct {
+	ID            string
+	TaskID        string
+	TestName      string
+	ProcessName   string
+	Tags          []string
+	Execution     int
+	TimeRange     util.TimeRange
+	PrintTime     bool
+	PrintPriority bool
+	Limit         int
+	Tail          int
+	SoftSizeLimit int
+
+	// AcceptEncoding is informational only; the connector always returns
+	// plaintext bytes and leaves transport compression to the REST layer.
+	AcceptEncoding string
+
+	// Match, Exclude, MinPriority, and Fields push server-side filtering
+	// down to the connector so that callers never have to pull an entire
+	// log across the wire just to grep it client-side.
+	Match       string
+	Exclude     string
+	MinPriority int
+	Fields      []string
+}
+
+// APILog is the metadata returned for a buildlogger log.
+type APILog struct {
+	ID          string   `json:"id"`
+	TaskID      string   `json:"task_id"`
+	TestName    string   `json:"test_name"`
+	Execution   int      `json:"execution"`
+	Tags        []string `json:"tags"`
+	CreatedAt   APITime  `json:"created_at"`
+	CompletedAt APITime  `json:"completed_at"`
+}
+
+// APITime marshals a time.Time as RFC3339 for API responses.
+type APITime time.Time
+
+// Connector mediates between the REST layer and the underlying buildlogger
+// storage. dbConnector is the production implementation; tests exercise it
+// with fake ChunkStore and ChunkResolver implementations.
+type Connector interface {
+	FindLogByID(ctx context.Context, opts BuildloggerOptions) (data []byte, next time.Time, paginated bool, err error)
+	FindLogsByTaskID(ctx context.Context, opts BuildloggerOptions) (data []byte, next time.Time, paginated bool, err error)
+	FindLogsByTestName(ctx context.Context, opts BuildloggerOptions) (data []byte, next time.Time, paginated bool, err error)
+	FindGroupedLogs(ctx context.Context, opts BuildloggerOptions) (data []byte, next time.Time, paginated bool, err error)
+	FindLogMetadataByID(ctx context.Context, id string) (*APILog, error)
+	FindLogMetadataByTaskID(ctx context.Context, opts BuildloggerOptions) ([]APILog, error)
+	FindLogMetadataByTestName(ctx context.Context, opts BuildloggerOptions) ([]APILog, error)
+}
+
+// ChunkStore fetches and stores raw log chunk bytes in the backing bucket.
+// Chunks may be stored compressed (see CompressOldChunks); codec names the
+// compression applied to raw, or codecNone if raw is already plaintext.
+type ChunkStore interface {
+	Chunk(ctx context.Context, key string) (raw []byte, codec string, err error)
+	PutChunk(ctx context.Context, key string, raw []byte, codec string) error
+}
+
+// ChunkResolver maps a task id, test name, or group lookup to the ordered
+// list of chunk keys backing it, so FindLogsByTaskID, FindLogsByTestName,
+// and FindGroupedLogs can fetch and filter each chunk the same way
+// FindLogByID does for a single log.
+type ChunkResolver interface {
+	ChunksByTaskID(ctx context.Context, opts BuildloggerOptions) ([]string, error)
+	ChunksByTestName(ctx context.Context, opts BuildloggerOptions) ([]string, error)
+	ChunksForGroup(ctx context.Context, opts BuildloggerOptions) ([]string, error)
+}
+
+// dbConnector is the production Connector implementation, backed by a
+// ChunkStore for log bytes and a ChunkResolver for looking up chunks by task
+// id, test name, or group. It holds no metadata store, so its
+// FindLogMetadata* methods return an error.
+type dbConnector struct {
+	chunks   ChunkStore
+	resolver ChunkResolver
+}
+
+// NewDBConnector returns a Connector backed by the given chunk store and
+// resolver.
+func NewDBConnector(chunks ChunkStore, resolver ChunkResolver) Connector {
+	return &dbConnector{chunks: chunks, resolver: resolver}
+}
+
+// FindLogByID fetches and decompresses the chunk for a single log, then
+// applies opts.Match, opts.Exclude, opts.MinPriority, and opts.Fields.
+// Chunks may have been rewritten by CompressOldChunks since they were
+// first written, so decompression always runs before filtering,
+// regardless of which codec (if any) the chunk is currently stored under.
+func (c *dbConnector) FindLogByID(ctx context.Context, opts BuildloggerOptions) ([]byte, time.Time, bool, error) {
+	raw, codec, err := c.chunks.Chunk(ctx, opts.ID)
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrapf(err, "problem fetching chunk for log '%s'", opts.ID)
+	}
+
+	plain, err := decompressChunk(raw, codec)
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrapf(err, "problem decompressing chunk for log '%s'", opts.ID)
+	}
+
+	result, err := FilterChunk(opts, plain)
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrapf(err, "problem filtering log '%s'", opts.ID)
+	}
+
+	return result.Data, result.Next, false, nil
+}
+
+// FindLogsByTaskID resolves the chunks for a task's logs and fetches,
+// decompresses, and filters each in turn, the same as FindLogByID does for
+// a single chunk.
+func (c *dbConnector) FindLogsByTaskID(ctx context.Context, opts BuildloggerOptions) ([]byte, time.Time, bool, error) {
+	keys, err := c.resolver.ChunksByTaskID(ctx, opts)
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrapf(err, "problem resolving chunks for task '%s'", opts.TaskID)
+	}
+
+	return c.findLogsByKeys(ctx, opts, keys)
+}
+
+// FindLogsByTestName resolves the chunks for a test's logs and fetches,
+// decompresses, and filters each in turn, the same as FindLogByID does for
+// a single chunk.
+func (c *dbConnector) FindLogsByTestName(ctx context.Context, opts BuildloggerOptions) ([]byte, time.Time, bool, error) {
+	keys, err := c.resolver.ChunksByTestName(ctx, opts)
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrapf(err, "problem resolving chunks for test '%s'", opts.TestName)
+	}
+
+	return c.findLogsByKeys(ctx, opts, keys)
+}
+
+// FindGroupedLogs resolves the chunks for a group of logs and fetches,
+// decompresses, and filters each in turn, the same as FindLogByID does for
+// a single chunk.
+func (c *dbConnector) FindGroupedLogs(ctx context.Context, opts BuildloggerOptions) ([]byte, time.Time, bool, error) {
+	keys, err := c.resolver.ChunksForGroup(ctx, opts)
+	if err != nil {
+		return nil, time.Time{}, false, errors.Wrapf(err, "problem resolving chunks for task '%s'", opts.TaskID)
+	}
+
+	return c.findLogsByKeys(ctx, opts, keys)
+}
+
+// findLogsByKeys fetches, decompresses, and filters each chunk key in
+// order, concatenating the surviving bytes. It stops early and reports
+// paginated once the accumulated size reaches opts.SoftSizeLimit, so a
+// single request can't be forced to buffer an unbounded number of chunks.
+func (c *dbConnector) findLogsByKeys(ctx context.Context, opts BuildloggerOptions, keys []string) ([]byte, time.Time, bool, error) {
+	buf := &bytes.Buffer{}
+	var next time.Time
+
+	for _, key := range keys {
+		raw, codec, err := c.chunks.Chunk(ctx, key)
+		if err != nil {
+			return nil, time.Time{}, false, errors.Wrapf(err, "problem fetching chunk '%s'", key)
+		}
+
+		plain, err := decompressChunk(raw, codec)
+		if err != nil {
+			return nil, time.Time{}, false, errors.Wrapf(err, "problem decompressing chunk '%s'", key)
+		}
+
+		result, err := FilterChunk(opts, plain)
+		if err != nil {
+			return nil, time.Time{}, false, errors.Wrapf(err, "problem filtering chunk '%s'", key)
+		}
+
+		buf.Write(result.Data)
+		if result.Next.After(next) {
+			next = result.Next
+		}
+
+		if opts.SoftSizeLimit > 0 && buf.Len() >= opts.SoftSizeLimit {
+			return buf.Bytes(), next, true, nil
+		}
+	}
+
+	return buf.Bytes(), next, false, nil
+}
+
+// FindLogMetadataByID is unimplemented pending a real metadata collection;
+// it exists so dbConnector satisfies Connector.
+func (c *dbConnector) FindLogMetadataByID(ctx context.Context, id string) (*APILog, error) {
+	return nil, errors.New("not implemented")
+}
+
+// FindLogMetadataByTaskID is unimplemented pending a real metadata
+// collection; it exists so dbConnector satisfies Connector.
+func (c *dbConnector) FindLogMetadataByTaskID(ctx context.Context, opts BuildloggerOptions) ([]APILog, error) {
+	return nil, errors.New("not implemented")
+}
+
+// FindLogMetadataByTestName is unimplemented pending a real metadata
+// collection; it exists so dbConnector satisfies Connector.
+func (c *dbConnector) FindLogMetadataByTestName(ctx context.Context, opts BuildloggerOptions) ([]APILog, error) {
+	return nil, errors.New("not implemented")
+}
+
+// compressChunkBytes compresses plaintext bytes with the given codec.
+// codecNone returns plain unchanged.
+func compressChunkBytes(plain []byte, codec string) ([]byte, error) {
+	switch codec {
+	case codecNone:
+		return plain, nil
+	case codecGzip:
+		buf := &bytes.Buffer{}
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(plain); err != nil {
+			return nil, errors.Wrap(err, "problem gzip compressing chunk")
+		}
+		if err := w.Close(); err != nil {
+			return nil, errors.Wrap(err, "problem closing gzip writer")
+		}
+		return buf.Bytes(), nil
+	case codecZstd:
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "problem creating zstd writer")
+		}
+		defer w.Close()
+		return w.EncodeAll(plain, nil), nil
+	default:
+		return nil, errors.Errorf("unrecognized chunk codec '%s'", codec)
+	}
+}
+
+// decompressChunk returns the plaintext bytes for a chunk stored under the
+// given codec, decompressing it if necessary. codecNone returns raw
+// unchanged.
+func decompressChunk(raw []byte, codec string) ([]byte, error) {
+	switch codec {
+	case codecNone:
+		return raw, nil
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, errors.Wrap(err, "problem creating gzip reader")
+		}
+		defer r.Close()
+		plain, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "problem reading gzip-compressed chunk")
+		}
+		return plain, nil
+	case codecZstd:
+		r, err := zstd.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, errors.Wrap(err, "problem creating zstd reader")
+		}
+		defer r.Close()
+		plain, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "problem reading zstd-compressed chunk")
+		}
+		return plain, nil
+	default:
+		return nil, errors.Errorf("unrecognized chunk codec '%s'", codec)
+	}
+}
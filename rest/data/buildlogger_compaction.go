@@ -0,0 +1,68 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// ChunkLister enumerates chunk keys eligible for compaction, oldest first.
+// A production ChunkStore backed by pail should implement this alongside
+// Chunk/PutChunk.
+type ChunkLister interface {
+	UncompactedChunks(ctx context.Context, olderThan time.Time) ([]string, error)
+}
+
+// CompressOldChunks recompresses every chunk older than age that isn't
+// already stored with targetCodec, decompressing it first if it was
+// written with a different codec. It's meant to run as a periodic
+// background job, not inline with request handling, so a single slow
+// chunk never adds latency to a GET request. Callers should pick age to
+// leave recently written chunks alone, since those are still likely to be
+// read by an active task.
+func CompressOldChunks(ctx context.Context, store ChunkStore, lister ChunkLister, targetCodec string, age time.Duration) error {
+	keys, err := lister.UncompactedChunks(ctx, time.Now().Add(-age))
+	if err != nil {
+		return errors.Wrap(err, "problem listing chunks eligible for compaction")
+	}
+
+	catcher := grip.NewBasicCatcher()
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "compaction job canceled")
+		}
+
+		if err := compressChunk(ctx, store, key, targetCodec); err != nil {
+			catcher.Add(errors.Wrapf(err, "problem compacting chunk '%s'", key))
+		}
+	}
+
+	return catcher.Resolve()
+}
+
+// compressChunk decompresses a single chunk's current bytes, recompresses
+// them with targetCodec, and writes the result back if the codec actually
+// changed.
+func compressChunk(ctx context.Context, store ChunkStore, key, targetCodec string) error {
+	raw, codec, err := store.Chunk(ctx, key)
+	if err != nil {
+		return errors.Wrap(err, "problem fetching chunk")
+	}
+	if codec == targetCodec {
+		return nil
+	}
+
+	plain, err := decompressChunk(raw, codec)
+	if err != nil {
+		return errors.Wrap(err, "problem decompressing chunk")
+	}
+
+	compressed, err := compressChunkBytes(plain, targetCodec)
+	if err != nil {
+		return errors.Wrap(err, "problem recompressing chunk")
+	}
+
+	return errors.Wrap(store.PutChunk(ctx, key, compressed, targetCodec), "problem writing recompressed chunk")
+}
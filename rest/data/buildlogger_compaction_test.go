@@ -0,0 +1,131 @@
+package data
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeChunkStore is an in-memory ChunkStore/ChunkLister used to exercise
+// decompression and compaction without a real bucket.
+type fakeChunkStore struct {
+	raw           map[string][]byte
+	codec         map[string]string
+	lastOlderThan time.Time
+}
+
+func newFakeChunkStore() *fakeChunkStore {
+	return &fakeChunkStore{raw: map[string][]byte{}, codec: map[string]string{}}
+}
+
+func (s *fakeChunkStore) Chunk(ctx context.Context, key string) ([]byte, string, error) {
+	return s.raw[key], s.codec[key], nil
+}
+
+func (s *fakeChunkStore) PutChunk(ctx context.Context, key string, raw []byte, codec string) error {
+	s.raw[key] = raw
+	s.codec[key] = codec
+	return nil
+}
+
+func (s *fakeChunkStore) UncompactedChunks(ctx context.Context, olderThan time.Time) ([]string, error) {
+	s.lastOlderThan = olderThan
+
+	var keys []string
+	for key, codec := range s.codec {
+		if codec != codecZstd {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func gzipBytes(t *testing.T, plain []byte) []byte {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressChunkRoundTripsGzip(t *testing.T) {
+	plain := []byte("line one\nline two\n")
+	raw := gzipBytes(t, plain)
+
+	got, err := decompressChunk(raw, codecGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatalf("got %q, want %q", got, plain)
+	}
+}
+
+func TestDecompressChunkPassesThroughUncompressed(t *testing.T) {
+	plain := []byte("line one\n")
+	got, err := decompressChunk(plain, codecNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatalf("got %q, want %q", got, plain)
+	}
+}
+
+func TestCompressOldChunksRecompressesWithTargetCodec(t *testing.T) {
+	store := newFakeChunkStore()
+	plain := []byte("line one\nline two\n")
+	store.raw["chunk1"] = plain
+	store.codec["chunk1"] = codecNone
+
+	if err := CompressOldChunks(context.Background(), store, store, codecZstd, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.codec["chunk1"] != codecZstd {
+		t.Fatalf("expected chunk1 to be recompressed with zstd, got codec %q", store.codec["chunk1"])
+	}
+
+	roundTripped, err := decompressChunk(store.raw["chunk1"], store.codec["chunk1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(roundTripped, plain) {
+		t.Fatalf("recompressed chunk didn't round-trip: got %q, want %q", roundTripped, plain)
+	}
+}
+
+func TestCompressOldChunksSkipsChunksAlreadyAtTargetCodec(t *testing.T) {
+	store := newFakeChunkStore()
+	store.raw["chunk1"] = []byte("already zstd")
+	store.codec["chunk1"] = codecZstd
+
+	if err := CompressOldChunks(context.Background(), store, store, codecZstd, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(store.raw["chunk1"], []byte("already zstd")) {
+		t.Fatal("expected an already-compacted chunk to be left untouched")
+	}
+}
+
+func TestCompressOldChunksUsesConfiguredAge(t *testing.T) {
+	store := newFakeChunkStore()
+	before := time.Now()
+
+	if err := CompressOldChunks(context.Background(), store, store, codecZstd, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBefore := before.Add(-time.Hour)
+	wantAfter := time.Now().Add(-time.Hour)
+	if store.lastOlderThan.Before(wantBefore) || store.lastOlderThan.After(wantAfter) {
+		t.Fatalf("got olderThan %v, want it to reflect the configured 1h age (between %v and %v)", store.lastOlderThan, wantBefore, wantAfter)
+	}
+}
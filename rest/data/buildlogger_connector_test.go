@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeChunkResolver maps task ids, test names, and groups to chunk keys for
+// TestDBConnectorFindLogsByTaskID and friends.
+type fakeChunkResolver struct {
+	byTaskID   map[string][]string
+	byTestName map[string][]string
+	byGroup    map[string][]string
+}
+
+func (r *fakeChunkResolver) ChunksByTaskID(ctx context.Context, opts BuildloggerOptions) ([]string, error) {
+	return r.byTaskID[opts.TaskID], nil
+}
+
+func (r *fakeChunkResolver) ChunksByTestName(ctx context.Context, opts BuildloggerOptions) ([]string, error) {
+	return r.byTestName[opts.TestName], nil
+}
+
+func (r *fakeChunkResolver) ChunksForGroup(ctx context.Context, opts BuildloggerOptions) ([]string, error) {
+	return r.byGroup[opts.TaskID], nil
+}
+
+func TestDBConnectorFindLogsByTaskIDConcatenatesAndFiltersChunks(t *testing.T) {
+	store := newFakeChunkStore()
+	ts := time.Unix(100, 0)
+	store.raw["chunk1"] = []byte(line(ts, 0, "keep me") + "\n")
+	store.codec["chunk1"] = codecNone
+	store.raw["chunk2"] = []byte(line(ts, 0, "drop me") + "\n")
+	store.codec["chunk2"] = codecNone
+
+	resolver := &fakeChunkResolver{byTaskID: map[string][]string{"task1": {"chunk1", "chunk2"}}}
+	conn := NewDBConnector(store, resolver)
+
+	data, _, paginated, err := conn.FindLogsByTaskID(context.Background(), BuildloggerOptions{TaskID: "task1", Match: "keep"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paginated {
+		t.Fatal("did not expect pagination without a SoftSizeLimit")
+	}
+	if !strings.Contains(string(data), "keep me") || strings.Contains(string(data), "drop me") {
+		t.Fatalf("got %q, want only the matching chunk's line", data)
+	}
+}
+
+func TestDBConnectorFindLogsByTaskIDStopsAtSoftSizeLimit(t *testing.T) {
+	store := newFakeChunkStore()
+	ts := time.Unix(100, 0)
+	store.raw["chunk1"] = []byte(line(ts, 0, "first chunk") + "\n")
+	store.codec["chunk1"] = codecNone
+	store.raw["chunk2"] = []byte(line(ts, 0, "second chunk") + "\n")
+	store.codec["chunk2"] = codecNone
+
+	resolver := &fakeChunkResolver{byTaskID: map[string][]string{"task1": {"chunk1", "chunk2"}}}
+	conn := NewDBConnector(store, resolver)
+
+	data, _, paginated, err := conn.FindLogsByTaskID(context.Background(), BuildloggerOptions{TaskID: "task1", SoftSizeLimit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !paginated {
+		t.Fatal("expected pagination once the soft size limit was reached")
+	}
+	if !strings.Contains(string(data), "first chunk") {
+		t.Fatalf("got %q, want the first chunk's line", data)
+	}
+}
+
+func TestDBConnectorFindLogsByTestNameUsesTestNameResolver(t *testing.T) {
+	store := newFakeChunkStore()
+	ts := time.Unix(100, 0)
+	store.raw["chunk1"] = []byte(line(ts, 0, "a test log line") + "\n")
+	store.codec["chunk1"] = codecNone
+
+	resolver := &fakeChunkResolver{byTestName: map[string][]string{"TestFoo": {"chunk1"}}}
+	conn := NewDBConnector(store, resolver)
+
+	data, _, _, err := conn.FindLogsByTestName(context.Background(), BuildloggerOptions{TestName: "TestFoo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "a test log line") {
+		t.Fatalf("got %q, want the resolved chunk's line", data)
+	}
+}
+
+func TestDBConnectorFindGroupedLogsUsesGroupResolver(t *testing.T) {
+	store := newFakeChunkStore()
+	ts := time.Unix(100, 0)
+	store.raw["chunk1"] = []byte(line(ts, 0, "grouped line") + "\n")
+	store.codec["chunk1"] = codecNone
+
+	resolver := &fakeChunkResolver{byGroup: map[string][]string{"task1": {"chunk1"}}}
+	conn := NewDBConnector(store, resolver)
+
+	data, _, _, err := conn.FindGroupedLogs(context.Background(), BuildloggerOptions{TaskID: "task1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "grouped line") {
+		t.Fatalf("got %q, want the resolved chunk's line", data)
+	}
+}
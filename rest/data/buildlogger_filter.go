@@ -0,0 +1,137 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// FilterResult is the outcome of applying a BuildloggerOptions filter to a
+// chunk of raw log lines.
+type FilterResult struct {
+	Data  []byte
+	Count int
+	Next  time.Time
+}
+
+// FilterChunk applies opts.TimeRange.StartAt, opts.Match, opts.Exclude,
+// opts.MinPriority, and opts.Fields to a chunk of raw log lines, one per
+// line in the storage line format "<unix nanos>\t<priority>\t<message>".
+// Lines that fail any filter are dropped entirely; Fields controls which
+// parts of a surviving line are kept in the output, and Next is the
+// latest timestamp among the surviving lines, for resuming a stream or
+// paginated request. Callers that poll repeatedly (runBuildloggerStream) pass the
+// previous call's Next back in as opts.TimeRange.StartAt so each poll only
+// returns lines appended since the last one, instead of the whole chunk.
+func FilterChunk(opts BuildloggerOptions, raw []byte) (FilterResult, error) {
+	var matchRe, excludeRe *regexp.Regexp
+	var err error
+	if opts.Match != "" {
+		if matchRe, err = regexp.Compile(opts.Match); err != nil {
+			return FilterResult{}, errors.Wrapf(err, "problem compiling match regexp '%s'", opts.Match)
+		}
+	}
+	if opts.Exclude != "" {
+		if excludeRe, err = regexp.Compile(opts.Exclude); err != nil {
+			return FilterResult{}, errors.Wrapf(err, "problem compiling exclude regexp '%s'", opts.Exclude)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	var count int
+	var last time.Time
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		ts, priority, message := splitLogLine(line)
+		if !opts.TimeRange.StartAt.IsZero() && !ts.After(opts.TimeRange.StartAt) {
+			continue
+		}
+		if !opts.TimeRange.EndAt.IsZero() && ts.After(opts.TimeRange.EndAt) {
+			continue
+		}
+		if priority < opts.MinPriority {
+			continue
+		}
+		if matchRe != nil && !matchRe.MatchString(message) {
+			continue
+		}
+		if excludeRe != nil && excludeRe.MatchString(message) {
+			continue
+		}
+
+		count++
+		if ts.After(last) {
+			last = ts
+		}
+
+		buf.WriteString(projectFields(opts.Fields, ts, priority, message))
+		buf.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return FilterResult{}, errors.Wrap(err, "problem scanning log chunk")
+	}
+
+	return FilterResult{Data: buf.Bytes(), Count: count, Next: last}, nil
+}
+
+// splitLogLine parses a single "<unix nanos>\t<priority>\t<message>"
+// storage line. A malformed line is treated as an unprioritized message
+// with a zero timestamp, so filtering degrades gracefully instead of
+// dropping unparseable data outright.
+func splitLogLine(line string) (time.Time, int, string) {
+	parts := strings.SplitN(line, "\t", 3)
+	if len(parts) != 3 {
+		return time.Time{}, 0, line
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, line
+	}
+	priority, err := parseLinePriority(parts[1])
+	if err != nil {
+		return time.Time{}, 0, line
+	}
+
+	return time.Unix(0, nanos), priority, parts[2]
+}
+
+// parseLinePriority parses a storage line's priority field.
+func parseLinePriority(s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+// projectFields renders a surviving line, restricted to the requested
+// fields ("time", "priority", "message"); an empty fields list keeps every
+// field, matching the unfiltered storage line format.
+func projectFields(fields []string, ts time.Time, priority int, message string) string {
+	if len(fields) == 0 {
+		return strings.Join([]string{strconv.FormatInt(ts.UnixNano(), 10), strconv.Itoa(priority), message}, "\t")
+	}
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "time":
+			parts = append(parts, ts.Format(time.RFC3339Nano))
+		case "priority":
+			parts = append(parts, strconv.Itoa(priority))
+		case "message":
+			parts = append(parts, message)
+		}
+	}
+
+	return strings.Join(parts, "\t")
+}
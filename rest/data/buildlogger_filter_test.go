@@ -0,0 +1,137 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evergreen-ci/cedar/util"
+)
+
+func line(ts time.Time, priority int, message string) string {
+	return strings.Join([]string{
+		strconv.FormatInt(ts.UnixNano(), 10),
+		strconv.Itoa(priority),
+		message,
+	}, "\t")
+}
+
+func TestFilterChunkMatchOnlyKeepsMatchingLines(t *testing.T) {
+	ts := time.Unix(100, 0)
+	raw := []byte(line(ts, 0, "hello world") + "\n" + line(ts, 0, "goodbye world") + "\n")
+
+	result, err := FilterChunk(BuildloggerOptions{Match: "^hello"}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 1 {
+		t.Fatalf("got count %d, want 1", result.Count)
+	}
+	if !strings.Contains(string(result.Data), "hello world") {
+		t.Fatalf("expected surviving line to contain 'hello world', got %q", result.Data)
+	}
+}
+
+func TestFilterChunkExcludeDropsMatchingLines(t *testing.T) {
+	ts := time.Unix(100, 0)
+	raw := []byte(line(ts, 0, "hello world") + "\n" + line(ts, 0, "goodbye world") + "\n")
+
+	result, err := FilterChunk(BuildloggerOptions{Exclude: "goodbye"}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 1 {
+		t.Fatalf("got count %d, want 1", result.Count)
+	}
+	if strings.Contains(string(result.Data), "goodbye") {
+		t.Fatalf("expected excluded line to be dropped, got %q", result.Data)
+	}
+}
+
+func TestFilterChunkMinPriorityDropsLowerPriorityLines(t *testing.T) {
+	ts := time.Unix(100, 0)
+	raw := []byte(line(ts, 10, "low") + "\n" + line(ts, 50, "high") + "\n")
+
+	result, err := FilterChunk(BuildloggerOptions{MinPriority: 30}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 1 {
+		t.Fatalf("got count %d, want 1", result.Count)
+	}
+	if !strings.Contains(string(result.Data), "high") {
+		t.Fatalf("expected surviving line to contain 'high', got %q", result.Data)
+	}
+}
+
+func TestFilterChunkFieldsProjection(t *testing.T) {
+	ts := time.Unix(100, 0)
+	raw := []byte(line(ts, 5, "a message") + "\n")
+
+	result, err := FilterChunk(BuildloggerOptions{Fields: []string{"message"}}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(result.Data)) != "a message" {
+		t.Fatalf("got %q, want just the message field", result.Data)
+	}
+}
+
+func TestFilterChunkRejectsInvalidMatchRegexp(t *testing.T) {
+	if _, err := FilterChunk(BuildloggerOptions{Match: "("}, nil); err == nil {
+		t.Fatal("expected an error for an unparsable match regexp")
+	}
+}
+
+func TestFilterChunkSecondPollWithNoNewLinesReturnsNothing(t *testing.T) {
+	ts := time.Unix(100, 0)
+	raw := []byte(line(ts, 0, "only line") + "\n")
+
+	first, err := FilterChunk(BuildloggerOptions{}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Count != 1 {
+		t.Fatalf("got count %d, want 1", first.Count)
+	}
+
+	second, err := FilterChunk(BuildloggerOptions{TimeRange: util.TimeRange{StartAt: first.Next}}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Count != 0 || len(second.Data) != 0 {
+		t.Fatalf("expected re-polling the same chunk to return nothing, got count %d data %q", second.Count, second.Data)
+	}
+}
+
+func TestFilterChunkStartAtExcludesLinesAtOrBeforeIt(t *testing.T) {
+	earlier := time.Unix(100, 0)
+	later := time.Unix(200, 0)
+	raw := []byte(line(earlier, 0, "old") + "\n" + line(later, 0, "new") + "\n")
+
+	result, err := FilterChunk(BuildloggerOptions{TimeRange: util.TimeRange{StartAt: earlier}}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 1 {
+		t.Fatalf("got count %d, want 1", result.Count)
+	}
+	if !strings.Contains(string(result.Data), "new") {
+		t.Fatalf("expected only the newer line to survive, got %q", result.Data)
+	}
+}
+
+func TestFilterChunkNextIsLatestSurvivingTimestamp(t *testing.T) {
+	earlier := time.Unix(100, 0)
+	later := time.Unix(200, 0)
+	raw := []byte(line(earlier, 0, "first") + "\n" + line(later, 0, "second") + "\n")
+
+	result, err := FilterChunk(BuildloggerOptions{}, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Next.Equal(later) {
+		t.Fatalf("got next %v, want %v", result.Next, later)
+	}
+}